@@ -0,0 +1,25 @@
+package main
+
+import "time"
+
+// Config 汇总 MkEpoll/NewServer 的可调参数。早期版本用位置参数传递
+// workers/queue/dupFDs 等选项，随着可调项增多（idle timeout、heartbeat）
+// 继续堆位置参数会让构造函数越来越难读，于是收拢成一个结构体。
+type Config struct {
+	// Workers/Queue 控制承接 Handler 回调的 worker pool 规模。
+	Workers int
+	Queue   int
+
+	// DupFDs 为 true 时，epoll 层注册的是每个连接 fd 的独立拷贝，
+	// 详见 epoll.dupFDs 字段注释。
+	DupFDs bool
+
+	// IdleTimeout 为正值时，连接超过这个时长没有任何成功的读/写就会被判定为
+	// 空闲并关闭；<= 0 表示不启用空闲超时检测。
+	IdleTimeout time.Duration
+
+	// Heartbeat 非空时，在真正关闭一个空闲连接之前，先给它发送一次这个 payload
+	// 作为应用层心跳包，并再给它一个 IdleTimeout 周期等待对端产生任何读写活动；
+	// 仍然空闲的话才会被关闭。Heartbeat 为空则空闲即关闭，不做心跳探测。
+	Heartbeat []byte
+}
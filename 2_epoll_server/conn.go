@@ -0,0 +1,142 @@
+//go:build linux
+// +build linux
+
+package main
+
+import (
+	"errors"
+	"net"
+	"sync"
+
+	"golang.org/x/sys/unix"
+)
+
+// ErrConnClosed 在连接已经被 removeFD 移除之后，业务代码仍然尝试写入时返回。
+var ErrConnClosed = errors.New("conn: use of closed connection")
+
+// Conn 是对单条 TCP 连接的封装，额外持有 outbound：一个待发送的字节切片队列，
+// 当 Write 跟不上 EPOLLOUT 速度时在这里排队。
+//
+// 入站方向没有额外的缓冲：drainRead 把每次 recv 到的分片直接拷贝一份交给
+// workerPool，真正的背压来自 workerPool 队列写满时 submit 的阻塞（见
+// workerpool.go），而不是某个本地缓冲区——本地缓冲区只会在没有消费者的情况下
+// 无限堆积或者永久写满，并不能替代"让生产者（这里是 drainRead）慢下来"这个
+// 背压的本质。
+//
+// 所有字段的读写都通过 mu 保护，因为 recv/flush 发生在 epoll 事件循环 goroutine，
+// 而业务代码可能从 worker pool 的任意 goroutine 调用 Conn.Write。
+type Conn struct {
+	fd           int
+	raw          net.Conn
+	e            *epoll
+	closed       bool
+	mu           sync.Mutex
+	outbound     [][]byte // 未发送完的数据块队列，outbound[0] 的前 outboundOff 字节已发出
+	outboundOff  int
+	wantWritable bool // 是否已经因为 outbound 非空而关注 EPOLLOUT
+}
+
+func newConn(fd int, raw net.Conn, e *epoll) *Conn {
+	return &Conn{
+		fd:  fd,
+		raw: raw,
+		e:   e,
+	}
+}
+
+// Write 将 p 追加到发送队列，并尝试立即 flush；若内核发送缓冲区已满（EAGAIN），
+// 剩余数据留在队列中，等待下一次 EPOLLOUT 边沿触发继续发送。
+func (c *Conn) Write(p []byte) error {
+	if len(p) == 0 {
+		return nil
+	}
+	buf := make([]byte, len(p))
+	copy(buf, p)
+
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return ErrConnClosed
+	}
+	c.outbound = append(c.outbound, buf)
+	c.mu.Unlock()
+
+	return c.flushOutbound()
+}
+
+// flushOutbound 在持有锁的情况下不断 write(2) 直到队列清空或遇到 EAGAIN，
+// 只有在队列非空时才需要继续关注 EPOLLOUT（这就是“只在有未发送数据时才重新
+// 关注可写事件”的由来，避免 ET 模式下 EPOLLOUT 空转）。
+func (c *Conn) flushOutbound() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return ErrConnClosed
+	}
+
+	for len(c.outbound) > 0 {
+		chunk := c.outbound[0][c.outboundOff:]
+		n, err := unix.Write(c.fd, chunk)
+		if n > 0 {
+			c.outboundOff += n
+			if c.e.expirer != nil {
+				c.e.expirer.touch(c)
+			}
+		}
+		if c.outboundOff == len(c.outbound[0]) {
+			c.outbound = c.outbound[1:]
+			c.outboundOff = 0
+		}
+		if err != nil {
+			if err == unix.EAGAIN {
+				break
+			}
+			return err
+		}
+		if n == 0 {
+			break
+		}
+	}
+
+	pending := len(c.outbound) > 0
+	if pending && !c.wantWritable {
+		c.wantWritable = true
+		return c.e.modify(c.fd, unix.EPOLLIN|unix.EPOLLOUT|unix.EPOLLRDHUP|unix.EPOLLET)
+	}
+	if !pending && c.wantWritable {
+		c.wantWritable = false
+		return c.e.modify(c.fd, unix.EPOLLIN|unix.EPOLLRDHUP|unix.EPOLLET)
+	}
+	return nil
+}
+
+// drainRead 在 EPOLLIN 边沿触发时被事件循环调用，循环 recv 直到 EAGAIN（ET 模式
+// 下每次就绪只通知一次，必须把内核缓冲区读空），把每次 recv 到的完整分片拷贝一份
+// 作为一条消息交给 Handler。背压由 pool.submit 承担：workerPool 的任务队列写满后
+// submit 会阻塞，从而拖慢这里的 recv 循环，既不会无限堆积，也不会丢数据。
+func (c *Conn) drainRead(h Handler, pool *workerPool) (closed bool) {
+	tmp := make([]byte, 4096)
+	for {
+		n, err := unix.Read(c.fd, tmp)
+		if n > 0 {
+			if c.e.expirer != nil {
+				c.e.expirer.touch(c)
+			}
+			data := make([]byte, n)
+			copy(data, tmp[:n])
+			pool.submit(func() { h.OnData(c, data) })
+		}
+		if err != nil {
+			if err == unix.EAGAIN {
+				break
+			}
+			// 0 或其他错误：对端关闭或连接出错
+			return true
+		}
+		if n == 0 {
+			return true
+		}
+	}
+	return false
+}
@@ -6,73 +6,159 @@ package main
 import (
 	"log"
 	"net"
-	"reflect"
 	"sync"
 	"syscall"
 
 	"golang.org/x/sys/unix"
 )
 
+// etEvents 是每个 fd 注册时关注的事件集合：EPOLLIN/EPOLLOUT 分别对应可读/可写，
+// EPOLLRDHUP 让我们能在对端半关闭（发送 FIN）时就感知到而不必等到真正读到 0 字节，
+// EPOLLET 开启边沿触发——每次状态变化只通知一次，因此下面的事件循环里对每个 fd
+// 都必须把数据"drain 到底"（recv/write 到 EAGAIN 为止），否则会漏掉还未读取的数据。
+const etEvents = unix.EPOLLIN | unix.EPOLLOUT | unix.EPOLLRDHUP | unix.EPOLLET
+
 type epoll struct {
 	fd int
 	// 使用这个 map 的原因在于：从 epoll_wait 上返回的时候，返回的是文件描述符，在 C 中可以直接针对文件描述符来操作 socket
 	// 但是在 Go 中，我们是通过 net.Conn 来操作网络 Socket 的，因此需要在 Go 语言层面上使用 map 来做一个额外的映射
-	connections map[int]net.Conn // key 为文件描述符，value 为 net.Conn 结构体
-	// 所有涉及 connections 上读写的操作都需要利用 lock 进行上锁
+	connections map[int]*Conn // key 为 epoll 实际注册的文件描述符，value 为对 net.Conn 的封装
+	byConn      map[net.Conn]*Conn
+	// 所有涉及 connections/byConn 上读写的操作都需要利用 lock 进行上锁
 	lock *sync.RWMutex // 读写锁
+
+	handler Handler
+	pool    *workerPool
+
+	// dupFDs 为 true 时，Add 不会直接把 conn 的 fd 交给 epoll_ctl，而是先
+	// fcntl(F_DUPFD_CLOEXEC) 复制一份独立的描述符再注册，避免 epoll 持有的
+	// fd 生命周期与 net.Conn/运行时 netpoller 的 fd 生命周期纠缠在一起。
+	dupFDs bool
+
+	// expirer 非 nil 时负责淘汰超过 cfg.IdleTimeout 没有任何读写活动的连接，
+	// 见 Config.IdleTimeout/Config.Heartbeat。
+	expirer *expirer
 }
 
-func MkEpoll() (*epoll, error) {
+// MkEpoll 创建一个 epoll 实例，h 是业务回调，cfg 控制 worker pool 规模、
+// dup 模式以及空闲连接超时/心跳——读写 callback 都在 pool 里执行，epoll
+// 事件循环自身不会阻塞。
+func MkEpoll(h Handler, cfg Config) (*epoll, error) {
 	// epoll_create 返回的是文件描述符
 	fd, err := unix.EpollCreate1(0)
 	if err != nil {
 		return nil, err
 	}
-	// 在 Go 中为文件描述符添加一层封装
-	return &epoll{
+	e := &epoll{
 		fd:          fd,
 		lock:        &sync.RWMutex{},
-		connections: make(map[int]net.Conn),
-	}, nil
+		connections: make(map[int]*Conn),
+		byConn:      make(map[net.Conn]*Conn),
+		handler:     h,
+		pool:        newWorkerPool(cfg.Workers, cfg.Queue),
+		dupFDs:      cfg.DupFDs,
+	}
+	if cfg.IdleTimeout > 0 {
+		e.expirer = newExpirer(cfg.IdleTimeout, cfg.Heartbeat)
+	}
+	return e, nil
 }
 
 func (e *epoll) Add(conn net.Conn) error {
-	// Extract file descriptor associated with the connection
-	fd := socketFD(conn)
-	err := unix.EpollCtl(e.fd, syscall.EPOLL_CTL_ADD, fd, &unix.EpollEvent{Events: unix.POLLIN | unix.POLLHUP, Fd: int32(fd)})
+	// 通过 syscall.Conn/RawConn.Control 取得连接对应的文件描述符，不再依赖
+	// reflect 去翻标准库内部未导出的 conn/fd/pfd/Sysfd 字段
+	fd, err := fdFromConn(conn)
 	if err != nil {
 		return err
 	}
+	if e.dupFDs {
+		dupped, err := dupCloexecFD(fd)
+		if err != nil {
+			return err
+		}
+		fd = dupped
+	}
+	if err := unix.EpollCtl(e.fd, syscall.EPOLL_CTL_ADD, fd, &unix.EpollEvent{Events: etEvents, Fd: int32(fd)}); err != nil {
+		if e.dupFDs {
+			unix.Close(fd)
+		}
+		return err
+	}
 	// 写锁
 	e.lock.Lock()
 	defer e.lock.Unlock()
-	// 注册一些 fd -> net.Conn 的映射逻辑
-	e.connections[fd] = conn
+	// 注册一些 fd -> *Conn 的映射逻辑
+	c := newConn(fd, conn, e)
+	e.connections[fd] = c
+	e.byConn[conn] = c
 	if len(e.connections)%100 == 0 {
 		log.Printf("total number of connections: %v", len(e.connections))
 	}
+	if e.expirer != nil {
+		e.expirer.add(c)
+	}
 	return nil
 }
 
+// modify 重新设置某个 fd 关注的事件集合，目前只用于在 outbound 队列非空/清空时
+// 切换是否关注 EPOLLOUT，避免 ET 模式下对一个一直可写的 fd 反复空转。
+func (e *epoll) modify(fd int, events uint32) error {
+	return unix.EpollCtl(e.fd, syscall.EPOLL_CTL_MOD, fd, &unix.EpollEvent{Events: events, Fd: int32(fd)})
+}
+
 func (e *epoll) Remove(conn net.Conn) error {
-	fd := socketFD(conn)
+	e.lock.RLock()
+	c, ok := e.byConn[conn]
+	e.lock.RUnlock()
+	if !ok {
+		return nil
+	}
+	return e.removeFD(c.fd)
+}
+
+// removeFD 是 Remove 的内部实现，同时被“主动移除”和“对端挂断/出错后被动移除”共用。
+func (e *epoll) removeFD(fd int) error {
 	// epoll 中移除管理
 	err := unix.EpollCtl(e.fd, syscall.EPOLL_CTL_DEL, fd, nil)
-	if err != nil {
+	if err != nil && err != unix.ENOENT {
 		return err
 	}
 	// 写锁
 	e.lock.Lock()
-	defer e.lock.Unlock()
+	c, ok := e.connections[fd]
 	// 删除映射关系
 	delete(e.connections, fd)
-	if len(e.connections)%100 == 0 {
-		log.Printf("total number of connections: %v", len(e.connections))
+	if ok {
+		delete(e.byConn, c.raw)
+	}
+	count := len(e.connections)
+	e.lock.Unlock()
+	if count%100 == 0 {
+		log.Printf("total number of connections: %v", count)
+	}
+	if ok {
+		if e.expirer != nil {
+			e.expirer.remove(c)
+		}
+		c.mu.Lock()
+		c.closed = true
+		c.mu.Unlock()
+		if e.dupFDs {
+			// dup 出来的描述符只有 epoll 层自己持有，这里负责关闭它
+			unix.Close(fd)
+		}
+		// 不管是不是 dup 模式，原始 net.Conn 都在 Add 时交给了 epoll 层管理
+		// 并且从未交还给调用方，所以必须在这里关闭它，否则每一次移除都会泄漏
+		// 一个 socket fd，最终拖垮整个进程的 fd 上限。
+		c.raw.Close()
+		e.pool.submit(func() { e.handler.OnClose(c) })
 	}
 	return nil
 }
 
-func (e *epoll) Wait() ([]net.Conn, error) {
+// Wait 执行一次 epoll_wait 并返回本轮就绪的 epoll 事件（fd + 事件位），供调用方（通常是 Serve）
+// 决定如何处理；由于是 ET 模式，真正的“读到底/写到底”逻辑在 Serve 里完成。
+func (e *epoll) Wait() ([]unix.EpollEvent, error) {
 	events := make([]unix.EpollEvent, 100)
 retry:
 	n, err := unix.EpollWait(e.fd, events, 100)
@@ -83,31 +169,63 @@ retry:
 		}
 		return nil, err
 	}
-	// 上读锁
-	e.lock.RLock()
-	defer e.lock.RUnlock()
-	var connections []net.Conn // result to return
-	for i := 0; i < n; i++ {
-		conn := e.connections[int(events[i].Fd)] // map
-		connections = append(connections, conn)
+	return events[:n], nil
+}
+
+// Serve 是 ET 模式下的主事件循环：每次 Wait() 返回后，对每个就绪 fd 按照
+// EPOLLRDHUP/EPOLLHUP -> EPOLLOUT -> EPOLLIN 的顺序处理，直到调用方关闭 epoll。
+// 所有 Handler 回调都通过 workerPool 派发，循环本身不会被用户代码阻塞。
+func (e *epoll) Serve() error {
+	for {
+		events, err := e.Wait()
+		if err != nil {
+			if err == unix.EBADF {
+				return nil
+			}
+			return err
+		}
+		for _, ev := range events {
+			fd := int(ev.Fd)
+			e.lock.RLock()
+			c := e.connections[fd]
+			e.lock.RUnlock()
+			if c == nil {
+				continue
+			}
+
+			if ev.Events&(unix.EPOLLHUP|unix.EPOLLRDHUP|unix.EPOLLERR) != 0 {
+				e.removeFD(fd)
+				continue
+			}
+			if ev.Events&unix.EPOLLOUT != 0 {
+				// 只有此前因 outbound 堆积而关注了 EPOLLOUT 的连接才回调 OnWritable
+				c.mu.Lock()
+				backlogged := c.wantWritable
+				c.mu.Unlock()
+				if err := c.flushOutbound(); err != nil && err != unix.EAGAIN {
+					e.removeFD(fd)
+					continue
+				}
+				if backlogged {
+					e.pool.submit(func() { e.handler.OnWritable(c) })
+				}
+			}
+			if ev.Events&unix.EPOLLIN != 0 {
+				if closed := c.drainRead(e.handler, e.pool); closed {
+					e.removeFD(fd)
+				}
+			}
+		}
 	}
-	return connections, nil
 }
 
-// socketFD 将 net.Coon 转换为文件描述符
-func socketFD(conn net.Conn) int {
-	//tls := reflect.TypeOf(conn.UnderlyingConn()) == reflect.TypeOf(&tls.Conn{})
-	// Extract the file descriptor associated with the connection
-	//connVal := reflect.Indirect(reflect.ValueOf(conn)).FieldByName("conn").Elem()
-	// net.Conn 的实现 net.TCPConn 结构体中有一个私有的 conn 字段，
-	tcpConn := reflect.Indirect(reflect.ValueOf(conn)).FieldByName("conn")
-	//if tls {
-	//	tcpConn = reflect.Indirect(tcpConn.Elem())
-	//}
-	// 类似的逻辑 ...
-	fdVal := tcpConn.FieldByName("fd")
-	// pdf 的语义是 File descriptor of poll(epoll)
-	pfdVal := reflect.Indirect(fdVal).FieldByName("pfd")
-	// 反正最后返回的是 Socket 对应的文件描述符
-	return int(pfdVal.FieldByName("Sysfd").Int())
+// Close 关闭 epoll 文件描述符（使 Serve 里的 EpollWait 以 EBADF 返回从而退出循环）
+// 并等待 worker pool 里已入队的回调执行完毕。
+func (e *epoll) Close() error {
+	err := unix.Close(e.fd)
+	if e.expirer != nil {
+		e.expirer.close()
+	}
+	e.pool.close()
+	return err
 }
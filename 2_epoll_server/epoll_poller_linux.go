@@ -0,0 +1,90 @@
+//go:build linux
+// +build linux
+
+package main
+
+import (
+	"net"
+	"sync"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// epollPoller 是 Poller 接口在 Linux 上的实现，基于 epoll。它和 epoll_linux.go
+// 里的 epoll 类型是两个独立的东西：epoll 是本包原有的、带 ET/Handler/worker
+// pool 的高吞吐运行时，epollPoller 只实现 Poller 这个最小的跨平台接口，按
+// Wait() 调用方的节奏返回就绪连接，采用水平触发语义：连接上只要还有未读数据，
+// 下一次 Wait 仍会返回它。这一点与使用 EV_CLEAR（边沿触发）的 kqueuePoller
+// 不同；Windows 上没有对应实现（见 ErrIOCPUnsupported）。
+type epollPoller struct {
+	fd          int
+	connections map[int]net.Conn
+	lock        sync.RWMutex
+}
+
+func newEpollPoller() (*epollPoller, error) {
+	fd, err := unix.EpollCreate1(0)
+	if err != nil {
+		return nil, err
+	}
+	return &epollPoller{fd: fd, connections: make(map[int]net.Conn)}, nil
+}
+
+func (p *epollPoller) Add(conn net.Conn) error {
+	fd, err := fdFromConn(conn)
+	if err != nil {
+		return err
+	}
+	if err := unix.EpollCtl(p.fd, syscall.EPOLL_CTL_ADD, fd, &unix.EpollEvent{Events: unix.EPOLLIN | unix.EPOLLRDHUP, Fd: int32(fd)}); err != nil {
+		return err
+	}
+	p.lock.Lock()
+	p.connections[fd] = conn
+	p.lock.Unlock()
+	return nil
+}
+
+func (p *epollPoller) Remove(conn net.Conn) error {
+	fd, err := fdFromConn(conn)
+	if err != nil {
+		return err
+	}
+	if err := unix.EpollCtl(p.fd, syscall.EPOLL_CTL_DEL, fd, nil); err != nil && err != unix.ENOENT {
+		return err
+	}
+	p.lock.Lock()
+	delete(p.connections, fd)
+	p.lock.Unlock()
+	return nil
+}
+
+func (p *epollPoller) Wait() ([]net.Conn, error) {
+	events := make([]unix.EpollEvent, 100)
+retry:
+	n, err := unix.EpollWait(p.fd, events, 100)
+	if err != nil {
+		if err == unix.EINTR {
+			goto retry
+		}
+		return nil, err
+	}
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	conns := make([]net.Conn, 0, n)
+	for i := 0; i < n; i++ {
+		if c, ok := p.connections[int(events[i].Fd)]; ok {
+			conns = append(conns, c)
+		}
+	}
+	return conns, nil
+}
+
+func (p *epollPoller) Close() error {
+	return unix.Close(p.fd)
+}
+
+// MkPoller 返回当前 GOOS（Linux）对应的 Poller 实现。
+func MkPoller() (Poller, error) {
+	return newEpollPoller()
+}
@@ -0,0 +1,145 @@
+//go:build linux
+// +build linux
+
+package main
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// sweepInterval 是后台 goroutine 检查过期连接的节拍。
+const sweepInterval = time.Second
+
+// expirer 按最后活跃时间淘汰空闲连接。实现上没有用最小堆，而是用一个按
+// “最后活跃时间”排序的双向链表：每次 touch 把对应节点移到链表尾部，这是
+// O(1) 操作；sweep 只需要从链表头部开始看，一旦遇到还没超时的节点就可以
+// 停止，不必扫描全部连接——这就是时间轮/LRU 链表在这个场景下的标准用法。
+type expirer struct {
+	idleTimeout time.Duration
+	heartbeat   []byte
+
+	mu    sync.Mutex
+	order *list.List // 按 lastActive 升序排列，最老的在最前面
+	elems map[*Conn]*list.Element
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+type expiryEntry struct {
+	c          *Conn
+	lastActive time.Time
+	pinged     bool // 是否已经发送过心跳包、正在等待这最后一个 IdleTimeout 周期
+}
+
+func newExpirer(idleTimeout time.Duration, heartbeat []byte) *expirer {
+	e := &expirer{
+		idleTimeout: idleTimeout,
+		heartbeat:   heartbeat,
+		order:       list.New(),
+		elems:       make(map[*Conn]*list.Element),
+		stop:        make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+	go e.run()
+	return e
+}
+
+// add 在连接刚建立时把它加入过期跟踪，初始 lastActive 为当前时间。
+func (e *expirer) add(c *Conn) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	elem := e.order.PushBack(&expiryEntry{c: c, lastActive: time.Now()})
+	e.elems[c] = elem
+}
+
+// touch 在一次成功的读或写之后调用，刷新 lastActive 并把节点移到链表尾部，
+// 同时清除“已发送心跳、等待应答”的标记——收到任何数据都说明连接仍然存活。
+func (e *expirer) touch(c *Conn) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	elem, ok := e.elems[c]
+	if !ok {
+		return
+	}
+	entry := elem.Value.(*expiryEntry)
+	entry.lastActive = time.Now()
+	entry.pinged = false
+	e.order.MoveToBack(elem)
+}
+
+// remove 在连接被关闭/移除时调用，停止继续跟踪它。
+func (e *expirer) remove(c *Conn) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	elem, ok := e.elems[c]
+	if !ok {
+		return
+	}
+	e.order.Remove(elem)
+	delete(e.elems, c)
+}
+
+func (e *expirer) close() {
+	close(e.stop)
+	<-e.done
+}
+
+func (e *expirer) run() {
+	defer close(e.done)
+	ticker := time.NewTicker(sweepInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-e.stop:
+			return
+		case now := <-ticker.C:
+			e.sweep(now)
+		}
+	}
+}
+
+// sweep 从链表头部（最久未活跃）开始检查，一旦遇到还没超过 idleTimeout 的
+// 连接就可以停止——链表本身按 lastActive 有序，后面的只会更新。
+func (e *expirer) sweep(now time.Time) {
+	var toHeartbeat, toClose []*Conn
+
+	e.mu.Lock()
+	for elem := e.order.Front(); elem != nil; {
+		entry := elem.Value.(*expiryEntry)
+		idleFor := now.Sub(entry.lastActive)
+		if idleFor < e.idleTimeout {
+			break
+		}
+		next := elem.Next()
+		if len(e.heartbeat) > 0 && !entry.pinged {
+			// 先发一次心跳，再给它一个完整的 IdleTimeout 周期等待任何响应
+			entry.pinged = true
+			entry.lastActive = now
+			e.order.MoveToBack(elem)
+			toHeartbeat = append(toHeartbeat, entry.c)
+		} else {
+			e.order.Remove(elem)
+			delete(e.elems, entry.c)
+			toClose = append(toClose, entry.c)
+		}
+		elem = next
+	}
+	e.mu.Unlock()
+
+	for _, c := range toHeartbeat {
+		c.Write(e.heartbeat)
+		// 心跳包自身的写入会经 flushOutbound 调用 touch 清掉 pinged，这里重新
+		// 标记，使心跳之后的那个周期里只有别的读写活动才能让连接免于关闭
+		e.mu.Lock()
+		if elem, ok := e.elems[c]; ok {
+			elem.Value.(*expiryEntry).pinged = true
+		}
+		e.mu.Unlock()
+	}
+	for _, c := range toClose {
+		c.e.removeFD(c.fd)
+	}
+}
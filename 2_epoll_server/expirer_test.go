@@ -0,0 +1,132 @@
+//go:build linux
+// +build linux
+
+package main
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+// fakeConn is a minimal net.Conn whose Close is a no-op, just enough for
+// removeFD's bookkeeping path in these tests.
+type fakeConn struct{ net.Conn }
+
+func (fakeConn) Close() error { return nil }
+
+type noopHandler struct{}
+
+func (noopHandler) OnData(*Conn, []byte) {}
+func (noopHandler) OnClose(*Conn)        {}
+func (noopHandler) OnWritable(*Conn)     {}
+
+func newTestEpoll(t *testing.T) *epoll {
+	t.Helper()
+	e, err := MkEpoll(noopHandler{}, Config{Workers: 1, Queue: 1})
+	if err != nil {
+		t.Fatalf("MkEpoll: %v", err)
+	}
+	t.Cleanup(func() { e.Close() })
+	return e
+}
+
+func TestExpirerTouchReordersToBack(t *testing.T) {
+	e := newTestEpoll(t)
+	exp := newExpirer(time.Hour, nil)
+	defer exp.close()
+
+	// 故意复用 0/1/2（stdin/stdout/stderr）这几个在任何进程里都保证存在的 fd：
+	// 这些测试绕过了 epoll.Add，直接构造 Conn 并只验证 expirer 自身的簿记，
+	// 所以只需要 fd 在 epoll_ctl(DEL) 时是一个合法的打开描述符（返回 ENOENT
+	// 而不是 EBADF），具体指向哪个文件无关紧要。
+	a := newConn(0, fakeConn{}, e)
+	b := newConn(1, fakeConn{}, e)
+	c := newConn(2, fakeConn{}, e)
+
+	exp.add(a)
+	exp.add(b)
+	exp.add(c)
+
+	// touch a：它应该从最前面移动到最后面
+	exp.touch(a)
+
+	exp.mu.Lock()
+	order := make([]*Conn, 0, 3)
+	for el := exp.order.Front(); el != nil; el = el.Next() {
+		order = append(order, el.Value.(*expiryEntry).c)
+	}
+	exp.mu.Unlock()
+
+	if len(order) != 3 || order[0] != b || order[1] != c || order[2] != a {
+		t.Fatalf("order after touch(a) = %v, want [b c a]", order)
+	}
+}
+
+func TestExpirerSweepClosesOnlyIdleConnsWithoutHeartbeat(t *testing.T) {
+	e := newTestEpoll(t)
+	exp := newExpirer(10*time.Millisecond, nil)
+	defer exp.close()
+
+	stale := newConn(0, fakeConn{}, e)
+	fresh := newConn(1, fakeConn{}, e)
+
+	exp.add(stale)
+	base := time.Now()
+	exp.mu.Lock()
+	exp.elems[stale].Value.(*expiryEntry).lastActive = base.Add(-time.Hour)
+	exp.mu.Unlock()
+
+	exp.add(fresh) // 刚刚加入，肯定还没超时
+
+	exp.sweep(base)
+
+	exp.mu.Lock()
+	_, staleStillTracked := exp.elems[stale]
+	_, freshStillTracked := exp.elems[fresh]
+	exp.mu.Unlock()
+
+	if staleStillTracked {
+		t.Fatalf("stale conn should have been swept and untracked")
+	}
+	if !freshStillTracked {
+		t.Fatalf("fresh conn should still be tracked")
+	}
+}
+
+func TestExpirerSweepHeartbeatsBeforeClosing(t *testing.T) {
+	e := newTestEpoll(t)
+	heartbeat := []byte("ping")
+	exp := newExpirer(10*time.Millisecond, heartbeat)
+	defer exp.close()
+
+	c := newConn(0, fakeConn{}, e)
+	exp.add(c)
+	base := time.Now()
+	exp.mu.Lock()
+	exp.elems[c].Value.(*expiryEntry).lastActive = base.Add(-time.Hour)
+	exp.mu.Unlock()
+
+	// 第一次 sweep：应当只是发心跳、重置计时，而不是直接关闭连接
+	exp.sweep(base)
+
+	exp.mu.Lock()
+	entry, stillTracked := exp.elems[c]
+	exp.mu.Unlock()
+	if !stillTracked {
+		t.Fatalf("conn should still be tracked after first (heartbeat) sweep")
+	}
+	if !entry.Value.(*expiryEntry).pinged {
+		t.Fatalf("entry should be marked pinged after heartbeat sweep")
+	}
+
+	// 第二次 sweep：已经过了心跳后的等待周期，应当被真正关闭
+	exp.sweep(base.Add(time.Hour))
+
+	exp.mu.Lock()
+	_, stillTracked = exp.elems[c]
+	exp.mu.Unlock()
+	if stillTracked {
+		t.Fatalf("conn should have been closed after the grace period elapsed")
+	}
+}
@@ -0,0 +1,46 @@
+//go:build linux || darwin || dragonfly || freebsd || netbsd || openbsd
+// +build linux darwin dragonfly freebsd netbsd openbsd
+
+package main
+
+import (
+	"errors"
+	"net"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// errNotSyscallConn 表示传入的 net.Conn 没有实现 syscall.Conn，因而无法取出其
+// 底层文件描述符——目前只有 *net.TCPConn/*net.UnixConn 这类标准库类型满足。
+var errNotSyscallConn = errors.New("net.Conn does not implement syscall.Conn")
+
+// fdFromConn 通过标准库公开的 syscall.Conn/RawConn.Control 取得 conn 的文件
+// 描述符，供 kqueuePoller/epollPoller 注册使用。
+func fdFromConn(conn net.Conn) (int, error) {
+	sc, ok := conn.(syscall.Conn)
+	if !ok {
+		return 0, errNotSyscallConn
+	}
+	raw, err := sc.SyscallConn()
+	if err != nil {
+		return 0, err
+	}
+	var fd int
+	ctrlErr := raw.Control(func(f uintptr) {
+		fd = int(f)
+	})
+	if ctrlErr != nil {
+		return 0, ctrlErr
+	}
+	return fd, nil
+}
+
+// dupCloexecFD 通过 fcntl(F_DUPFD_CLOEXEC) 复制出一个与 fd 生命周期独立的新
+// 描述符。两者指向同一个打开文件描述（同一个 socket），但各自可以独立
+// close，这样 epoll 层持有的描述符就不会受 Go 运行时对原始 net.Conn 的
+// finalizer/Close 影响——两者曾经用同一个 fd 时，就存在 epoll_ctl 和
+// runtime netpoller 的 Close 产生竞态、导致 fd 被错误地复用到另一个连接上的风险。
+func dupCloexecFD(fd int) (int, error) {
+	return unix.FcntlInt(uintptr(fd), unix.F_DUPFD_CLOEXEC, 0)
+}
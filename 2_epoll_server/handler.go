@@ -0,0 +1,16 @@
+//go:build linux
+// +build linux
+
+package main
+
+// Handler 是用户需要实现的业务回调接口，epoll 事件循环自身永远不会阻塞在这些
+// 回调里——它们统一经由 workerPool 分发到独立的 goroutine 中执行。
+type Handler interface {
+	// OnData 在某个连接上读到一段完整的数据时被调用，data 仅在本次调用期间有效。
+	OnData(c *Conn, data []byte)
+	// OnClose 在连接被移除（对端关闭、RDHUP、出错）之后调用，用于清理业务状态。
+	OnClose(c *Conn)
+	// OnWritable 在此前因写缓冲区堆积而被限流的连接重新变为可写时调用，
+	// 供业务代码恢复继续写入。
+	OnWritable(c *Conn)
+}
@@ -0,0 +1,22 @@
+//go:build windows
+// +build windows
+
+package main
+
+import "errors"
+
+// ErrIOCPUnsupported 说明了为什么 Windows 上暂时没有 Poller 实现：IOCP 是一个
+// 完成端口模型——一个连接只有在提交了 overlapped ReadFile/WSARecv 之后，内核才
+// 会在数据读完时投递一个 completion，completion 里直接带着已经读到的数据。这和
+// Poller 接口（Wait 只返回"就绪"的连接列表，调用方随后自己 Read）假设的水平/边沿
+// 触发的就绪通知模型并不是一回事：要支持 IOCP，Poller 要么换成"返回已读数据"的
+// 接口，要么在这一层之上另外维护每个连接的 overlapped 读缓冲区并在 Wait 里把数据
+// 转手交出去。这两种都是明确的后续工作，在那之前，与其提交一个表面上实现了
+// CreateIoCompletionPort/GetQueuedCompletionStatusEx、实际上永远不会报告就绪的
+// 骨架，不如让 MkPoller 在 Windows 上直接返回这个错误。
+var ErrIOCPUnsupported = errors.New("poller: IOCP backend not implemented yet, see ErrIOCPUnsupported doc comment")
+
+// MkPoller 在 Windows 上暂未提供 Poller 实现，见 ErrIOCPUnsupported。
+func MkPoller() (Poller, error) {
+	return nil, ErrIOCPUnsupported
+}
@@ -0,0 +1,93 @@
+//go:build darwin || dragonfly || freebsd || netbsd || openbsd
+// +build darwin dragonfly freebsd netbsd openbsd
+
+package main
+
+import (
+	"net"
+	"sync"
+
+	"golang.org/x/sys/unix"
+)
+
+// kqueuePoller 是 Poller 接口在 macOS/BSD 上基于 kqueue 的实现，使用
+// EV_ADD|EV_CLEAR 注册 EVFILT_READ，EV_CLEAR 对应 epoll 里的边沿触发语义——
+// 每次状态变化只投递一次事件，但这里只把它当成"有数据可读"的信号传回调用方，
+// 真正的读循环仍由调用方负责。与水平触发的 epollPoller 不同，一次没有读完的
+// 数据不会在下一次 Wait 中再次报告。
+type kqueuePoller struct {
+	fd          int
+	connections map[int]net.Conn
+	lock        sync.RWMutex
+}
+
+func newKqueuePoller() (*kqueuePoller, error) {
+	fd, err := unix.Kqueue()
+	if err != nil {
+		return nil, err
+	}
+	return &kqueuePoller{fd: fd, connections: make(map[int]net.Conn)}, nil
+}
+
+func (p *kqueuePoller) Add(conn net.Conn) error {
+	fd, err := fdFromConn(conn)
+	if err != nil {
+		return err
+	}
+	ev := make([]unix.Kevent_t, 1)
+	unix.SetKevent(&ev[0], fd, unix.EVFILT_READ, unix.EV_ADD|unix.EV_CLEAR)
+	if _, err := unix.Kevent(p.fd, ev, nil, nil); err != nil {
+		return err
+	}
+	p.lock.Lock()
+	p.connections[fd] = conn
+	p.lock.Unlock()
+	return nil
+}
+
+func (p *kqueuePoller) Remove(conn net.Conn) error {
+	fd, err := fdFromConn(conn)
+	if err != nil {
+		return err
+	}
+	ev := make([]unix.Kevent_t, 1)
+	unix.SetKevent(&ev[0], fd, unix.EVFILT_READ, unix.EV_DELETE)
+	// 连接可能已经被对端关闭、kqueue 自动移除了该事件，这里忽略 ENOENT
+	if _, err := unix.Kevent(p.fd, ev, nil, nil); err != nil && err != unix.ENOENT {
+		return err
+	}
+	p.lock.Lock()
+	delete(p.connections, fd)
+	p.lock.Unlock()
+	return nil
+}
+
+func (p *kqueuePoller) Wait() ([]net.Conn, error) {
+	events := make([]unix.Kevent_t, 100)
+retry:
+	n, err := unix.Kevent(p.fd, nil, events, nil)
+	if err != nil {
+		if err == unix.EINTR {
+			goto retry
+		}
+		return nil, err
+	}
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	conns := make([]net.Conn, 0, n)
+	for i := 0; i < n; i++ {
+		if c, ok := p.connections[int(events[i].Ident)]; ok {
+			conns = append(conns, c)
+		}
+	}
+	return conns, nil
+}
+
+func (p *kqueuePoller) Close() error {
+	return unix.Close(p.fd)
+}
+
+// MkPoller 返回当前 GOOS（macOS/BSD 系）对应的 Poller 实现。
+func MkPoller() (Poller, error) {
+	return newKqueuePoller()
+}
@@ -0,0 +1,19 @@
+package main
+
+import "net"
+
+// Poller 是对平台相关的 I/O 多路复用机制（Linux epoll / BSD·macOS kqueue）
+// 的统一抽象，使上层代码不必关心具体运行在哪个 GOOS 上。Windows 上没有基于
+// IOCP 的实现，MkPoller 在那里返回 ErrIOCPUnsupported。
+// 这是一个比 epoll 类型更轻量的只读就绪通知原语：Wait 每次返回当前就绪的
+// 连接列表，调用方自行决定如何读写；epoll/Server/Handler 这一套边沿触发 +
+// 背压 + worker pool 的重量级运行时仍然只存在于 Linux 实现中，Poller 只是
+// 让"注册一个连接、等待它就绪"这件事本身变得跨平台。
+type Poller interface {
+	Add(net.Conn) error
+	Remove(net.Conn) error
+	Wait() ([]net.Conn, error)
+	Close() error
+}
+
+// MkPoller 返回适合当前 GOOS 的 Poller 实现，具体构造函数由各平台文件提供。
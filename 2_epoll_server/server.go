@@ -0,0 +1,140 @@
+//go:build linux
+// +build linux
+
+package main
+
+import (
+	"log"
+	"net"
+	"os"
+	"sync"
+
+	"golang.org/x/sys/unix"
+)
+
+// Server 把多个 SO_REUSEPORT 分片（shard）组合成一个million-connection 级别的
+// TCP 服务：每个分片拥有独立的监听 socket 和独立的 epoll 实例，互不共享锁，由
+// 内核在 SYN 到达时把连接散列到某个分片的监听 socket 上，从而消除单一 accept
+// goroutine 和单一 connections map 带来的锁竞争。
+type Server struct {
+	listeners []net.Listener
+	shards    []*epoll
+	wg        sync.WaitGroup
+}
+
+// NewServer 是本包的公开入口：在 addr 上启动 shards 个 SO_REUSEPORT 监听分片，
+// 每个分片拥有独立的 epoll 实例，按 cfg 配置 worker pool 规模、dup 模式与空闲
+// 超时/心跳。shards <= 0 时退化为单分片。cfg.DupFDs 固定为 true 更安全，这里
+// 不暴露给调用方选择。
+func NewServer(addr string, shards int, h Handler, cfg Config) (*Server, error) {
+	if shards <= 0 {
+		shards = 1
+	}
+	cfg.DupFDs = true
+
+	s := &Server{}
+	for i := 0; i < shards; i++ {
+		ln, err := reusePortListener(addr)
+		if err != nil {
+			s.Close()
+			return nil, err
+		}
+		s.listeners = append(s.listeners, ln)
+
+		ep, err := MkEpoll(h, cfg)
+		if err != nil {
+			s.Close()
+			return nil, err
+		}
+		s.shards = append(s.shards, ep)
+
+		s.wg.Add(2)
+		go func(ln net.Listener, ep *epoll) {
+			defer s.wg.Done()
+			s.acceptLoop(ln, ep)
+		}(ln, ep)
+		go func(ep *epoll) {
+			defer s.wg.Done()
+			if err := ep.Serve(); err != nil {
+				log.Printf("epoll shard exited: %v", err)
+			}
+		}(ep)
+	}
+	return s, nil
+}
+
+// acceptLoop 把某个分片监听 socket 上新到的连接注册进该分片自己的 epoll 实例。
+func (s *Server) acceptLoop(ln net.Listener, ep *epoll) {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		if err := ep.Add(conn); err != nil {
+			log.Printf("failed to add connection to epoll: %v", err)
+			conn.Close()
+		}
+	}
+}
+
+// Close 关闭全部监听 socket 与 epoll 分片，并等待 accept/事件循环 goroutine 退出。
+func (s *Server) Close() error {
+	for _, ln := range s.listeners {
+		ln.Close()
+	}
+	for _, ep := range s.shards {
+		ep.Close()
+	}
+	s.wg.Wait()
+	return nil
+}
+
+// reusePortListener 创建一个绑定了 SO_REUSEPORT 的 IPv4 TCP 监听 socket，使得
+// 多个分片可以各自 bind 同一个地址，由内核负责在它们之间做负载均衡。
+func reusePortListener(addr string) (net.Listener, error) {
+	tcpAddr, err := net.ResolveTCPAddr("tcp4", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	fd, err := unix.Socket(unix.AF_INET, unix.SOCK_STREAM, unix.IPPROTO_TCP)
+	if err != nil {
+		return nil, err
+	}
+	// 出错时需要自行关闭 fd，os.NewFile 接管之后由 f.Close() 负责关闭
+	ok := false
+	defer func() {
+		if !ok {
+			unix.Close(fd)
+		}
+	}()
+
+	if err := unix.SetsockoptInt(fd, unix.SOL_SOCKET, unix.SO_REUSEADDR, 1); err != nil {
+		return nil, err
+	}
+	if err := unix.SetsockoptInt(fd, unix.SOL_SOCKET, unix.SO_REUSEPORT, 1); err != nil {
+		return nil, err
+	}
+
+	var sa unix.SockaddrInet4
+	sa.Port = tcpAddr.Port
+	if tcpAddr.IP != nil {
+		copy(sa.Addr[:], tcpAddr.IP.To4())
+	}
+	if err := unix.Bind(fd, &sa); err != nil {
+		return nil, err
+	}
+	if err := unix.Listen(fd, unix.SOMAXCONN); err != nil {
+		return nil, err
+	}
+
+	f := os.NewFile(uintptr(fd), "reuseport-"+addr)
+	ok = true
+	// net.FileListener 会复制出自己的描述符，原始 fd 随 f.Close() 关闭
+	ln, err := net.FileListener(f)
+	f.Close()
+	if err != nil {
+		return nil, err
+	}
+	return ln, nil
+}
@@ -0,0 +1,42 @@
+package main
+
+import "sync"
+
+// workerPool 是一个固定数量 goroutine 的任务池，用来承接 epoll 事件循环派发出的
+// 回调任务，使得事件循环本身永远不会被用户代码阻塞。队列有界，一旦写满，submit
+// 会阻塞，从而把背压向上传导回事件循环（而不是无限制地堆积 goroutine）。
+type workerPool struct {
+	jobs chan func()
+	wg   sync.WaitGroup
+}
+
+func newWorkerPool(workers, queue int) *workerPool {
+	if workers <= 0 {
+		workers = 1
+	}
+	if queue <= 0 {
+		queue = workers
+	}
+	p := &workerPool{jobs: make(chan func(), queue)}
+	p.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer p.wg.Done()
+			for job := range p.jobs {
+				job()
+			}
+		}()
+	}
+	return p
+}
+
+// submit 将任务排入队列，队列满时会阻塞调用方。
+func (p *workerPool) submit(job func()) {
+	p.jobs <- job
+}
+
+// close 关闭任务队列并等待所有 worker 处理完已入队的任务。
+func (p *workerPool) close() {
+	close(p.jobs)
+	p.wg.Wait()
+}
@@ -0,0 +1,190 @@
+package ws
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// Opcode 是 RFC 6455 定义的帧操作码。
+type Opcode byte
+
+const (
+	OpContinuation Opcode = 0x0
+	OpText         Opcode = 0x1
+	OpBinary       Opcode = 0x2
+	OpClose        Opcode = 0x8
+	OpPing         Opcode = 0x9
+	OpPong         Opcode = 0xA
+)
+
+// Message 是分片合并之后交给业务层的一条完整消息（或者一个控制帧）。
+type Message struct {
+	Opcode  Opcode
+	Payload []byte
+}
+
+// errShortBuffer 表示目前累积的字节还不够解出下一帧，Decoder.Feed 会在下次
+// 收到更多字节后重试，不向上层报告为错误。
+var errShortBuffer = errors.New("ws: short buffer")
+
+// ErrUnexpectedContinuation 表示收到了一个延续帧，但此前并没有处于分片状态。
+var ErrUnexpectedContinuation = errors.New("ws: unexpected continuation frame")
+
+// ErrFrameTooLarge 表示帧头声明的 payload 长度超过了 MaxFrameLen，或者
+// 64 位扩展长度本身就不是一个合法的正数（例如高位被置位导致转换成 int 后为负）。
+// 这类帧会被直接拒绝而不是继续按声明长度去等待/切片，否则在 32/64 位平台上都
+// 可能因为长度溢出成负数而导致 slice bounds out of range 的 panic。
+var ErrFrameTooLarge = errors.New("ws: frame payload exceeds MaxFrameLen")
+
+// MaxFrameLen 是单个物理帧允许的最大 payload 长度，超过这个值的帧会被
+// decodeFrame 拒绝而不是被当作分配/切片的依据。
+const MaxFrameLen = 16 * 1024 * 1024 // 16 MiB
+
+// rawFrame 是单个物理帧，分片消息在 Decoder 内部被合并为 Message 之前都以
+// rawFrame 的形式存在。
+type rawFrame struct {
+	fin     bool
+	opcode  Opcode
+	payload []byte
+}
+
+// Decoder 是一个有状态的、增量的 WebSocket 帧解析器，每个连接一个实例。
+// Feed 每被调用一次就把新读到的数据追加到内部缓冲区，并尽可能多地解析、派发
+// 出已经完整到达的消息；不完整的帧会被原样留在缓冲区里，等待下一次 Feed。
+type Decoder struct {
+	buf []byte
+
+	fragmenting bool
+	fragOpcode  Opcode
+	fragPayload []byte
+}
+
+// NewDecoder 创建一个空的帧解码器。
+func NewDecoder() *Decoder {
+	return &Decoder{}
+}
+
+// Feed 追加 data 并解析出所有已经凑齐的消息，每解析出一条就调用一次 emit。
+// text/binary 帧如果没有设置 FIN，会被当成分片消息的第一帧缓存起来，直到后续
+// 的 continuation 帧（opcode 0）把 FIN 置位为止才合并成一条完整 Message 交给
+// emit；控制帧（PING/PONG/CLOSE）不允许分片，到达后立即交给 emit。
+func (d *Decoder) Feed(data []byte, emit func(Message)) error {
+	d.buf = append(d.buf, data...)
+
+	for {
+		frame, n, err := decodeFrame(d.buf)
+		if err == errShortBuffer {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		d.buf = d.buf[n:]
+
+		switch frame.opcode {
+		case OpPing, OpPong, OpClose:
+			emit(Message{Opcode: frame.opcode, Payload: frame.payload})
+		case OpContinuation:
+			if !d.fragmenting {
+				return ErrUnexpectedContinuation
+			}
+			d.fragPayload = append(d.fragPayload, frame.payload...)
+			if frame.fin {
+				emit(Message{Opcode: d.fragOpcode, Payload: d.fragPayload})
+				d.fragmenting = false
+				d.fragPayload = nil
+			}
+		default: // OpText, OpBinary
+			if frame.fin {
+				emit(Message{Opcode: frame.opcode, Payload: frame.payload})
+				continue
+			}
+			d.fragmenting = true
+			d.fragOpcode = frame.opcode
+			d.fragPayload = append([]byte(nil), frame.payload...)
+		}
+	}
+}
+
+// decodeFrame 尝试从 buf 开头解出一个完整的物理帧：2 字节基础头、可选的 16/64
+// 位扩展长度、客户端必须携带的 4 字节掩码，最后原地对 payload 做掩码还原。
+// 数据不够时返回 errShortBuffer，buf 保持不变，等待调用方喂入更多字节。
+func decodeFrame(buf []byte) (rawFrame, int, error) {
+	if len(buf) < 2 {
+		return rawFrame{}, 0, errShortBuffer
+	}
+	b0, b1 := buf[0], buf[1]
+	fin := b0&0x80 != 0
+	opcode := Opcode(b0 & 0x0F)
+	masked := b1&0x80 != 0
+	payloadLen := int(b1 & 0x7F)
+
+	idx := 2
+	switch payloadLen {
+	case 126:
+		if len(buf) < idx+2 {
+			return rawFrame{}, 0, errShortBuffer
+		}
+		payloadLen = int(binary.BigEndian.Uint16(buf[idx:]))
+		idx += 2
+	case 127:
+		if len(buf) < idx+8 {
+			return rawFrame{}, 0, errShortBuffer
+		}
+		length64 := binary.BigEndian.Uint64(buf[idx:])
+		if length64 > MaxFrameLen {
+			return rawFrame{}, 0, ErrFrameTooLarge
+		}
+		payloadLen = int(length64)
+		idx += 8
+	}
+	if payloadLen > MaxFrameLen {
+		return rawFrame{}, 0, ErrFrameTooLarge
+	}
+
+	var maskKey [4]byte
+	if masked {
+		if len(buf) < idx+4 {
+			return rawFrame{}, 0, errShortBuffer
+		}
+		copy(maskKey[:], buf[idx:idx+4])
+		idx += 4
+	}
+
+	if len(buf) < idx+payloadLen {
+		return rawFrame{}, 0, errShortBuffer
+	}
+	payload := buf[idx : idx+payloadLen]
+	if masked {
+		// 按 RFC 6455 原地对 payload 做掩码异或还原
+		for i := range payload {
+			payload[i] ^= maskKey[i%4]
+		}
+	}
+	// buf 随后会被 Decoder 整体前移/复用，这里必须拷贝一份供调用方长期持有
+	owned := make([]byte, len(payload))
+	copy(owned, payload)
+
+	return rawFrame{fin: fin, opcode: opcode, payload: owned}, idx + payloadLen, nil
+}
+
+// EncodeFrame 构造一个服务端到客户端的帧。服务端发送的帧按协议不能带掩码，
+// 本实现也不切分出站消息，总是以单个 FIN=1 帧发送。
+func EncodeFrame(opcode Opcode, payload []byte) []byte {
+	b0 := byte(0x80) | byte(opcode) // FIN=1
+
+	var header []byte
+	switch n := len(payload); {
+	case n <= 125:
+		header = []byte{b0, byte(n)}
+	case n <= 0xFFFF:
+		header = make([]byte, 4)
+		header[0], header[1] = b0, 126
+		binary.BigEndian.PutUint16(header[2:], uint16(n))
+	default:
+		header = make([]byte, 10)
+		header[0], header[1] = b0, 127
+		binary.BigEndian.PutUint64(header[2:], uint64(n))
+	}
+	return append(header, payload...)
+}
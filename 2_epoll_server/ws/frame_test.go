@@ -0,0 +1,119 @@
+package ws
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+// maskPayload 原地对 payload 做客户端掩码，供测试构造带掩码的帧。
+func maskPayload(payload []byte, key [4]byte) {
+	for i := range payload {
+		payload[i] ^= key[i%4]
+	}
+}
+
+func TestDecodeFrameUnmaskedSmallPayload(t *testing.T) {
+	payload := []byte("hello")
+	buf := append([]byte{0x80 | byte(OpText), byte(len(payload))}, payload...)
+
+	frame, n, err := decodeFrame(buf)
+	if err != nil {
+		t.Fatalf("decodeFrame: %v", err)
+	}
+	if n != len(buf) {
+		t.Fatalf("consumed = %d, want %d", n, len(buf))
+	}
+	if !frame.fin || frame.opcode != OpText || string(frame.payload) != "hello" {
+		t.Fatalf("frame = %+v", frame)
+	}
+}
+
+func TestDecodeFrameMaskedSmallPayload(t *testing.T) {
+	key := [4]byte{1, 2, 3, 4}
+	payload := []byte("hello")
+	masked := append([]byte(nil), payload...)
+	maskPayload(masked, key)
+
+	buf := []byte{0x80 | byte(OpText), 0x80 | byte(len(payload))}
+	buf = append(buf, key[:]...)
+	buf = append(buf, masked...)
+
+	frame, n, err := decodeFrame(buf)
+	if err != nil {
+		t.Fatalf("decodeFrame: %v", err)
+	}
+	if n != len(buf) {
+		t.Fatalf("consumed = %d, want %d", n, len(buf))
+	}
+	if string(frame.payload) != "hello" {
+		t.Fatalf("payload = %q, want %q", frame.payload, "hello")
+	}
+}
+
+func TestDecodeFrameExtended16Length(t *testing.T) {
+	payload := bytes.Repeat([]byte{'x'}, 200)
+	buf := []byte{0x80 | byte(OpBinary), 126, 0, 0}
+	binary.BigEndian.PutUint16(buf[2:4], uint16(len(payload)))
+	buf = append(buf, payload...)
+
+	frame, n, err := decodeFrame(buf)
+	if err != nil {
+		t.Fatalf("decodeFrame: %v", err)
+	}
+	if n != len(buf) || len(frame.payload) != len(payload) {
+		t.Fatalf("n=%d len(payload)=%d", n, len(frame.payload))
+	}
+}
+
+func TestDecodeFrameShortBufferWaitsForMore(t *testing.T) {
+	buf := []byte{0x80 | byte(OpText)} // 只有 1 字节，连基础头都不够
+	if _, _, err := decodeFrame(buf); err != errShortBuffer {
+		t.Fatalf("err = %v, want errShortBuffer", err)
+	}
+}
+
+// TestDecodeFrameRejectsOversizedExtendedLength 还原了一次真实的 panic：恶意
+// 客户端在 64 位扩展长度字段里填入最高位被置位的值，未加校验时转换成 int 会
+// 变成负数，随后的 slice bounds 检查形同虚设，最终在 buf[idx:idx+payloadLen]
+// 处以 "slice bounds out of range" panic 崩溃整个进程。
+func TestDecodeFrameRejectsOversizedExtendedLength(t *testing.T) {
+	buf := []byte{0x80 | byte(OpBinary), 127, 0, 0, 0, 0, 0, 0, 0, 0}
+	binary.BigEndian.PutUint64(buf[2:10], 0xFFFFFFFFFFFFFFFF)
+
+	_, _, err := decodeFrame(buf)
+	if err != ErrFrameTooLarge {
+		t.Fatalf("err = %v, want ErrFrameTooLarge", err)
+	}
+}
+
+func TestDecodeFrameRejectsPayloadOverMaxFrameLen(t *testing.T) {
+	buf := []byte{0x80 | byte(OpBinary), 127, 0, 0, 0, 0, 0, 0, 0, 0}
+	binary.BigEndian.PutUint64(buf[2:10], MaxFrameLen+1)
+
+	_, _, err := decodeFrame(buf)
+	if err != ErrFrameTooLarge {
+		t.Fatalf("err = %v, want ErrFrameTooLarge", err)
+	}
+}
+
+func TestDecoderFeedReassemblesFragmentedMessage(t *testing.T) {
+	d := NewDecoder()
+	var got []Message
+
+	first := []byte{0x00 | byte(OpText), 2, 'h', 'i'} // FIN=0
+	last := []byte{0x80 | byte(OpContinuation), 3, '!', '!', '!'}
+
+	if err := d.Feed(first, func(m Message) { got = append(got, m) }); err != nil {
+		t.Fatalf("Feed(first): %v", err)
+	}
+	if len(got) != 0 {
+		t.Fatalf("message emitted before FIN: %+v", got)
+	}
+	if err := d.Feed(last, func(m Message) { got = append(got, m) }); err != nil {
+		t.Fatalf("Feed(last): %v", err)
+	}
+	if len(got) != 1 || got[0].Opcode != OpText || string(got[0].Payload) != "hi!!!" {
+		t.Fatalf("got = %+v", got)
+	}
+}
@@ -0,0 +1,121 @@
+//go:build linux
+// +build linux
+
+package main
+
+import (
+	"sync"
+
+	"github.com/spongecaptain/1m-go-tcp-server/2_epoll_server/ws"
+)
+
+// wsHandshakeBufSize 是升级完成前 wsConnState.pending 允许累积的最大字节数，
+// 超过即关闭连接；pending 是按需增长的堆上切片，不是栈上缓冲区。
+// 一个 GET /... Upgrade 请求头远小于这个值。
+const wsHandshakeBufSize = 4096
+
+// WSMessageHandler 是 ws 升级成功之后，每收到一条完整消息时的业务回调。
+type WSMessageHandler func(c *Conn, opcode ws.Opcode, payload []byte)
+
+// wsConnState 记录单个连接的握手/解码状态。
+type wsConnState struct {
+	upgraded bool
+	pending  []byte // 升级完成前缓存的、尚不构成完整 HTTP 请求的字节
+	decoder  *ws.Decoder
+}
+
+// wsBridge 实现 Handler 接口，把裸字节流接到 ws 包提供的握手与帧解析逻辑上：
+// 升级之前按 HTTP 请求处理，升级之后把每次 OnData 收到的字节喂给该连接自己
+// 的 ws.Decoder，解码出的完整消息再转交给业务提供的 onMessage。
+type wsBridge struct {
+	onMessage WSMessageHandler
+
+	mu    sync.Mutex
+	state map[*Conn]*wsConnState
+}
+
+// NewWebSocketHandler 返回一个可以直接传给 MkEpoll/NewServer 的 Handler，
+// 它在裸 fd 上完成 WebSocket 握手和分帧，把完整消息交给 onMessage。
+func NewWebSocketHandler(onMessage WSMessageHandler) Handler {
+	return &wsBridge{
+		onMessage: onMessage,
+		state:     make(map[*Conn]*wsConnState),
+	}
+}
+
+func (b *wsBridge) stateFor(c *Conn) *wsConnState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	s, ok := b.state[c]
+	if !ok {
+		s = &wsConnState{}
+		b.state[c] = s
+	}
+	return s
+}
+
+func (b *wsBridge) OnData(c *Conn, data []byte) {
+	s := b.stateFor(c)
+
+	if !s.upgraded {
+		s.pending = append(s.pending, data...)
+		if len(s.pending) > wsHandshakeBufSize {
+			// 握手请求头异常地大，认为是恶意/非法连接，直接关闭
+			c.e.removeFD(c.fd)
+			return
+		}
+		key, n, err := ws.ParseUpgradeRequest(s.pending)
+		if err == ws.ErrIncompleteRequest {
+			return // 继续攒字节，等下一次 OnData
+		}
+		if err != nil {
+			c.e.removeFD(c.fd)
+			return
+		}
+		if werr := c.Write(ws.HandshakeResponse(key)); werr != nil {
+			c.e.removeFD(c.fd)
+			return
+		}
+		s.upgraded = true
+		s.decoder = ws.NewDecoder()
+		// 升级请求之后、本次 recv 里剩余的字节（如果有）可能已经是第一帧数据
+		rest := s.pending[n:]
+		s.pending = nil
+		if len(rest) == 0 {
+			return
+		}
+		data = rest
+	}
+
+	if err := s.decoder.Feed(data, func(msg ws.Message) {
+		b.dispatch(c, msg)
+	}); err != nil {
+		c.e.removeFD(c.fd)
+	}
+}
+
+// dispatch 处理单条解码出的消息：PING 自动回 PONG，CLOSE 触发优雅关闭，
+// 其余（合并分片之后的 text/binary）交给业务回调。
+func (b *wsBridge) dispatch(c *Conn, msg ws.Message) {
+	switch msg.Opcode {
+	case ws.OpPing:
+		c.Write(ws.EncodeFrame(ws.OpPong, msg.Payload))
+	case ws.OpPong:
+		// 无需处理，只是对端对我们发出的 PING 的应答
+	case ws.OpClose:
+		c.Write(ws.EncodeFrame(ws.OpClose, msg.Payload))
+		c.e.removeFD(c.fd)
+	default:
+		if b.onMessage != nil {
+			b.onMessage(c, msg.Opcode, msg.Payload)
+		}
+	}
+}
+
+func (b *wsBridge) OnClose(c *Conn) {
+	b.mu.Lock()
+	delete(b.state, c)
+	b.mu.Unlock()
+}
+
+func (b *wsBridge) OnWritable(c *Conn) {}